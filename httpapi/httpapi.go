@@ -0,0 +1,168 @@
+// Package httpapi exposes the current metrics over HTTP so users can wire
+// kweeb-logger into a browser dashboard or an existing Prometheus/Grafana
+// setup instead of polling the IPC socket directly. It is optional and
+// only started when --http is passed.
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Metrics mirrors the menubar's Metrics struct; httpapi keeps its own copy
+// so it doesn't depend on package main.
+type Metrics struct {
+	Keypresses      int     `json:"keypresses"`
+	MouseClicks     int     `json:"mouse_clicks"`
+	MouseDistanceIn float64 `json:"mouse_distance_in"`
+	MouseDistanceMi float64 `json:"mouse_distance_mi"`
+	ScrollSteps     int     `json:"scroll_steps"`
+}
+
+// State holds the latest metrics snapshot shared between the tray and the
+// HTTP server, guarded by a sync.RWMutex so both can read/write it
+// concurrently without racing.
+type State struct {
+	mu      sync.RWMutex
+	current Metrics
+
+	subMu       sync.Mutex
+	subscribers map[chan Metrics]struct{}
+}
+
+// NewState returns an empty, ready-to-use State.
+func NewState() *State {
+	return &State{subscribers: make(map[chan Metrics]struct{})}
+}
+
+// Set records the latest metrics snapshot and pushes it to every active
+// WebSocket subscriber.
+func (s *State) Set(m Metrics) {
+	s.mu.Lock()
+	s.current = m
+	s.mu.Unlock()
+
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- m:
+		default:
+			// Subscriber is behind; drop the frame rather than block Set.
+		}
+	}
+}
+
+// Get returns the latest metrics snapshot.
+func (s *State) Get() Metrics {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Subscribe registers a channel that receives future Set calls; a value is
+// dropped for this channel while its buffer of 8 is full.
+// Callers must invoke the returned unsubscribe func when done.
+func (s *State) Subscribe() (<-chan Metrics, func()) {
+	ch := make(chan Metrics, 8)
+
+	s.subMu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.subMu.Unlock()
+
+	unsubscribe := func() {
+		s.subMu.Lock()
+		delete(s.subscribers, ch)
+		s.subMu.Unlock()
+		close(ch)
+	}
+	return ch, unsubscribe
+}
+
+// Server serves /metrics (Prometheus), /api/v1/current (JSON), and /ws
+// (a push WebSocket) over the shared State.
+type Server struct {
+	state    *State
+	upgrader websocket.Upgrader
+}
+
+// NewServer returns a Server backed by state.
+func NewServer(state *State) *Server {
+	return &Server{
+		state: state,
+		upgrader: websocket.Upgrader{
+			// This server serves no HTML of its own, so any dashboard using
+			// it is necessarily a different origin than the listen address;
+			// the default same-origin check would reject every real client.
+			// --http is meant to be bound to loopback or a trusted network,
+			// not exposed directly to the internet, so allowing all origins
+			// here is the right tradeoff.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// Handler returns the server's http.Handler, useful for tests or embedding
+// behind an existing mux.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handlePrometheus)
+	mux.HandleFunc("/api/v1/current", s.handleCurrent)
+	mux.HandleFunc("/ws", s.handleWS)
+	return mux
+}
+
+// ListenAndServe starts the HTTP server on addr, e.g. ":7777".
+func (s *Server) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, s.Handler())
+}
+
+func (s *Server) handlePrometheus(w http.ResponseWriter, r *http.Request) {
+	m := s.state.Get()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP kweeb_keypresses_total Total keypresses recorded.")
+	fmt.Fprintln(w, "# TYPE kweeb_keypresses_total counter")
+	fmt.Fprintf(w, "kweeb_keypresses_total %d\n", m.Keypresses)
+
+	fmt.Fprintln(w, "# HELP kweeb_mouse_clicks_total Total mouse clicks recorded.")
+	fmt.Fprintln(w, "# TYPE kweeb_mouse_clicks_total counter")
+	fmt.Fprintf(w, "kweeb_mouse_clicks_total %d\n", m.MouseClicks)
+
+	fmt.Fprintln(w, "# HELP kweeb_mouse_distance_inches_total Total mouse travel distance, in inches.")
+	fmt.Fprintln(w, "# TYPE kweeb_mouse_distance_inches_total counter")
+	fmt.Fprintf(w, "kweeb_mouse_distance_inches_total %f\n", m.MouseDistanceIn)
+
+	fmt.Fprintln(w, "# HELP kweeb_scroll_steps_total Total scroll steps recorded.")
+	fmt.Fprintln(w, "# TYPE kweeb_scroll_steps_total counter")
+	fmt.Fprintf(w, "kweeb_scroll_steps_total %d\n", m.ScrollSteps)
+}
+
+func (s *Server) handleCurrent(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.state.Get())
+}
+
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ch, unsubscribe := s.state.Subscribe()
+	defer unsubscribe()
+
+	if err := conn.WriteJSON(s.state.Get()); err != nil {
+		return
+	}
+	for m := range ch {
+		if err := conn.WriteJSON(m); err != nil {
+			return
+		}
+	}
+}
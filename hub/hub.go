@@ -0,0 +1,51 @@
+// Package hub fans out published values to any number of subscribers,
+// decoupling producers from consumers the way Gorilla's websocket hub
+// pattern does. kweeb-logger uses it so the tray, the SQLite writer, and
+// the HTTP API can all consume the same metrics stream without the
+// connection handler that receives them knowing anything about its
+// consumers.
+package hub
+
+import "sync"
+
+// Hub fans out published values of type T to every registered subscriber
+// channel. The zero value is not usable; construct one with New.
+type Hub[T any] struct {
+	mu          sync.Mutex
+	subscribers map[chan<- T]struct{}
+}
+
+// New returns an empty, ready-to-use Hub.
+func New[T any]() *Hub[T] {
+	return &Hub[T]{subscribers: make(map[chan<- T]struct{})}
+}
+
+// Register adds ch to the set of subscribers that receive future Publish
+// calls. Registering the same channel twice is a no-op.
+func (h *Hub[T]) Register(ch chan<- T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers[ch] = struct{}{}
+}
+
+// Unregister removes ch from the subscriber set. It does not close ch;
+// the caller retains ownership of it.
+func (h *Hub[T]) Unregister(ch chan<- T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers, ch)
+}
+
+// Publish sends v to every registered subscriber. It never blocks: a
+// subscriber whose channel is full has this value dropped for it rather
+// than stalling the publisher.
+func (h *Hub[T]) Publish(v T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- v:
+		default:
+		}
+	}
+}
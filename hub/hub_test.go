@@ -0,0 +1,71 @@
+package hub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPublishFansOutToAllSubscribers(t *testing.T) {
+	h := New[int]()
+	a := make(chan int, 1)
+	b := make(chan int, 1)
+	h.Register(a)
+	h.Register(b)
+
+	h.Publish(42)
+
+	select {
+	case got := <-a:
+		if got != 42 {
+			t.Errorf("subscriber a got %d, want 42", got)
+		}
+	default:
+		t.Error("subscriber a received nothing")
+	}
+	select {
+	case got := <-b:
+		if got != 42 {
+			t.Errorf("subscriber b got %d, want 42", got)
+		}
+	default:
+		t.Error("subscriber b received nothing")
+	}
+}
+
+func TestPublishDropsForFullSubscriber(t *testing.T) {
+	h := New[int]()
+	full := make(chan int, 1)
+	full <- 1 // pre-fill so the next Publish has nowhere to go
+	h.Register(full)
+
+	done := make(chan struct{})
+	go func() {
+		h.Publish(2)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a full subscriber channel instead of dropping")
+	}
+
+	if got := <-full; got != 1 {
+		t.Errorf("full subscriber channel = %d, want original value 1 (2 should have been dropped)", got)
+	}
+}
+
+func TestUnregisterStopsFutureDeliveries(t *testing.T) {
+	h := New[int]()
+	ch := make(chan int, 1)
+	h.Register(ch)
+	h.Unregister(ch)
+
+	h.Publish(7)
+
+	select {
+	case got := <-ch:
+		t.Errorf("unregistered subscriber received %d, want nothing", got)
+	default:
+	}
+}
@@ -1,13 +1,25 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
 	"net"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
+	charmlog "github.com/charmbracelet/log"
+	"github.com/ethanpaneraa/kweeb-logger/httpapi"
+	"github.com/ethanpaneraa/kweeb-logger/hub"
+	"github.com/ethanpaneraa/kweeb-logger/protocol"
+	"github.com/ethanpaneraa/kweeb-logger/store"
+	"github.com/ethanpaneraa/kweeb-logger/transport"
 	"github.com/getlantern/systray"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 type Metrics struct {
@@ -18,85 +30,326 @@ type Metrics struct {
 	ScrollSteps     int     `json:"scroll_steps"`
 }
 
+// applyDelta overlays the non-nil fields of d onto a copy of m and returns
+// the result, leaving m untouched.
+func applyDelta(m Metrics, d protocol.MetricsDelta) Metrics {
+	if d.Keypresses != nil {
+		m.Keypresses = *d.Keypresses
+	}
+	if d.MouseClicks != nil {
+		m.MouseClicks = *d.MouseClicks
+	}
+	if d.MouseDistanceIn != nil {
+		m.MouseDistanceIn = *d.MouseDistanceIn
+	}
+	if d.MouseDistanceMi != nil {
+		m.MouseDistanceMi = *d.MouseDistanceMi
+	}
+	if d.ScrollSteps != nil {
+		m.ScrollSteps = *d.ScrollSteps
+	}
+	return m
+}
+
 var (
 	mKeyPresses    *systray.MenuItem
 	mMouseClicks   *systray.MenuItem
 	mMouseDistance *systray.MenuItem
 	mScrollSteps   *systray.MenuItem
-	listener       net.Listener
+	mToday         *systray.MenuItem
+	mThisWeek      *systray.MenuItem
+	mAllTime       *systray.MenuItem
+	mExportCSV     *systray.MenuItem
+	listener       transport.Listener
+	logger         *charmlog.Logger
+	metricsStore   *store.Store
+	apiState       = httpapi.NewState()
+	metricsHub     = hub.New[Metrics]()
+)
+
+var (
+	transportFlag = flag.String("transport", "", "IPC transport to use: unix[:path], pipe[:name] (Windows), or tcp:host:port (default: platform-appropriate unix socket/named pipe)")
+	logLevelFlag  = flag.String("log-level", "", "log level: debug, info, warn, error (default: info, overridable via KWEEB_LOG_LEVEL)")
+	logFileFlag   = flag.String("log-file", defaultLogFile(), "path to the rotating log file")
+	logFormatFlag = flag.String("log-format", "text", "log output format: text or json (json is easier for another process to ingest)")
+	storeFlag     = flag.String("store", defaultStorePath(), "path to the SQLite metrics database")
+	httpFlag      = flag.String("http", "", "address to serve the HTTP/WebSocket metrics API on, e.g. :7777 (disabled by default)")
 )
 
-const sockAddr = "/tmp/kawaiilogger.sock"
 var isMenuInitialized = false
 
+// defaultLogFile returns the rotating log sink's default path, under the
+// user's config directory so it survives reboots without cluttering $HOME.
+func defaultLogFile() string {
+	return filepath.Join(defaultConfigDir(), "kweeb.log")
+}
+
+// defaultStorePath returns the metrics database's default path, alongside
+// the log file.
+func defaultStorePath() string {
+	return filepath.Join(defaultConfigDir(), "metrics.db")
+}
+
+func defaultConfigDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "kweeb-logger")
+}
+
+func initLogger() {
+	fileSink := &lumberjack.Logger{
+		Filename:   *logFileFlag,
+		MaxSize:    10, // megabytes
+		MaxBackups: 5,
+		MaxAge:     28, // days
+	}
+
+	logger = charmlog.NewWithOptions(io.MultiWriter(os.Stderr, fileSink), charmlog.Options{
+		ReportTimestamp: true,
+		Prefix:          "⌨️ kweeb",
+		Formatter:       resolveLogFormat(),
+	})
+	logger.SetLevel(resolveLogLevel())
+}
+
+// resolveLogFormat applies --log-format, defaulting to text. An unrecognized
+// value falls back to text rather than failing startup.
+func resolveLogFormat() charmlog.Formatter {
+	switch *logFormatFlag {
+	case "json":
+		return charmlog.JSONFormatter
+	case "text", "":
+		return charmlog.TextFormatter
+	default:
+		fmt.Fprintf(os.Stderr, "kweeb: invalid log format %q, defaulting to text\n", *logFormatFlag)
+		return charmlog.TextFormatter
+	}
+}
+
+// resolveLogLevel applies --log-level, falling back to KWEEB_LOG_LEVEL and
+// then to info if neither is set or the value doesn't parse.
+func resolveLogLevel() charmlog.Level {
+	level := *logLevelFlag
+	if level == "" {
+		level = os.Getenv("KWEEB_LOG_LEVEL")
+	}
+	if level == "" {
+		return charmlog.InfoLevel
+	}
+
+	parsed, err := charmlog.ParseLevel(level)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kweeb: invalid log level %q, defaulting to info\n", level)
+		return charmlog.InfoLevel
+	}
+	return parsed
+}
+
 func main() {
-    log.Println("Starting Go application...")
+	flag.Parse()
+	initLogger()
+	logger.Info("starting kweeb-logger")
+
+	var err error
+	metricsStore, err = store.Open(*storeFlag)
+	if err != nil {
+		logger.Fatal("failed to open metrics store", "err", err)
+	}
 
-    if err := os.Remove(sockAddr); err != nil && !os.IsNotExist(err) {
-        log.Fatalf("Failed to remove existing socket file: %v", err)
-    }
+	if *httpFlag != "" {
+		go func() {
+			logger.Info("serving HTTP metrics API", "addr", *httpFlag)
+			srv := httpapi.NewServer(apiState)
+			if err := srv.ListenAndServe(*httpFlag); err != nil {
+				logger.Error("HTTP metrics API stopped", "err", err)
+			}
+		}()
+	}
+
+	registerHubSubscribers()
+	watchShutdownSignals()
+
+	go startSocketListener()
+	systray.Run(onReady, onExit)
+}
 
-    log.Println("Starting systray...")
-    go startSocketListener() 
-    systray.Run(onReady, onExit) 
+// watchShutdownSignals makes SIGINT/SIGTERM trigger the same graceful
+// shutdown as the "Quit" menu item, so Ctrl-C flushes the store and closes
+// the listener instead of the process dying mid-write. The transport
+// package registers its own handler too, but only to unlink its socket
+// file; it never decides to end the process itself.
+func watchShutdownSignals() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-sigCh
+		logger.Info("received shutdown signal", "signal", sig)
+		cleanup()
+		systray.Quit()
+	}()
+}
+
+// registerHubSubscribers wires up the tray, the SQLite writer, and the
+// HTTP API as independent subscribers of metricsHub, so ingestion doesn't
+// know or care who's consuming the stream.
+func registerHubSubscribers() {
+	trayCh := make(chan Metrics, 8)
+	metricsHub.Register(trayCh)
+	go func() {
+		for m := range trayCh {
+			updateMenuItems(&m)
+		}
+	}()
+
+	storeCh := make(chan Metrics, 64)
+	metricsHub.Register(storeCh)
+	go func() {
+		for m := range storeCh {
+			metricsStore.Record(store.Sample{
+				Timestamp:       time.Now(),
+				Keypresses:      m.Keypresses,
+				MouseClicks:     m.MouseClicks,
+				MouseDistanceIn: m.MouseDistanceIn,
+				ScrollSteps:     m.ScrollSteps,
+			})
+		}
+	}()
+
+	apiCh := make(chan Metrics, 8)
+	metricsHub.Register(apiCh)
+	go func() {
+		for m := range apiCh {
+			apiState.Set(httpapi.Metrics(m))
+		}
+	}()
 }
 
 func startSocketListener() {
-    log.Println("Creating Unix socket...")
-    var err error
-    listener, err = net.Listen("unix", sockAddr)
-    if err != nil {
-        log.Fatalf("Failed to create Unix socket: %v", err)
-    }
-    defer listener.Close()
-    log.Printf("Unix socket created at %s\n", sockAddr)
-
-    for {
-        conn, err := listener.Accept()
-        if err != nil {
-            log.Printf("Error accepting connection: %v", err)
-            continue
-        }
-        log.Println("Client connected")
-        go handleConnection(conn)
-    }
-}
-
-func connectToSocket() {
-	log.Println("Attempting to connect to socket...")
-	conn, err := net.Dial("unix", sockAddr)
+	logger.Debug("opening IPC transport", "spec", *transportFlag)
+	var err error
+	listener, err = transport.Listen(*transportFlag)
 	if err != nil {
-		log.Printf("Failed to connect to socket: %v\n", err)
-		return
+		logger.Fatal("failed to open transport", "err", err)
 	}
-	defer conn.Close()
-	log.Println("Successfully connected to socket")
+	defer listener.Close()
+	logger.Info("listening", "addr", listener.Addr())
 
-	handleConnection(conn)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				logger.Debug("listener closed, stopping accept loop")
+				return
+			}
+			logger.Error("accept failed", "err", err)
+			continue
+		}
+		logger.Info("client connected", "remote", conn.RemoteAddr())
+		go handleConnection(conn)
+	}
 }
 
+// handleConnection negotiates the handshake and then hands the connection
+// off to handleProducer or handleSubscriber depending on the role the peer
+// declared, so producers and subscribers can share the accept loop without
+// trampling each other's state.
 func handleConnection(conn net.Conn) {
-	buffer := make([]byte, 1024)
+	defer conn.Close()
+
+	version, peerRole, err := protocol.Negotiate(conn, protocol.RoleSubscriber)
+	if err != nil {
+		logger.Error("handshake failed", "err", err)
+		return
+	}
+	logger.Debug("negotiated handshake", "version", version, "peer_role", peerRole)
+
+	switch peerRole {
+	case protocol.RoleSubscriber:
+		handleSubscriber(conn)
+	default:
+		handleProducer(conn)
+	}
+}
+
+// handleProducer reads metrics frames off conn and publishes each
+// resulting snapshot to metricsHub; it has no idea who, if anyone, is
+// listening.
+func handleProducer(conn net.Conn) {
+	var last Metrics
 	for {
-		n, err := conn.Read(buffer)
+		msg, err := protocol.Decode(conn)
 		if err != nil {
-			log.Printf("Error reading from socket: %v\n", err)
+			logger.Error("read frame failed", "err", err)
 			return
 		}
 
-		var metrics Metrics
-		if err := json.Unmarshal(buffer[:n], &metrics); err != nil {
-			log.Printf("Error unmarshaling metrics: %v\n", err)
+		switch m := msg.(type) {
+		case protocol.MetricsSnapshot:
+			last = Metrics(m)
+		case protocol.MetricsDelta:
+			last = applyDelta(last, m)
+		case protocol.Heartbeat:
+			continue
+		case protocol.Reset:
+			last = Metrics{}
+		default:
+			logger.Warn("ignoring unexpected frame type", "type", fmt.Sprintf("%T", msg))
 			continue
 		}
 
-		log.Printf("Received metrics: %+v\n", metrics)
-		updateMenuItems(&metrics)
+		logger.Info("received metrics",
+			"keypresses", last.Keypresses,
+			"clicks", last.MouseClicks,
+			"scroll_steps", last.ScrollSteps,
+			"mouse_distance_in", last.MouseDistanceIn,
+		)
+		metricsHub.Publish(last)
+	}
+}
+
+// handleSubscriber registers conn with metricsHub and forwards each
+// published snapshot it receives to it as a MetricsSnapshot frame
+// (metricsHub drops snapshots while its 16-slot buffer is full), letting an external
+// process (a second tray, a CLI viewer) follow the same stream the tray
+// itself consumes.
+func handleSubscriber(conn net.Conn) {
+	ch := make(chan Metrics, 16)
+	metricsHub.Register(ch)
+	defer metricsHub.Unregister(ch)
+
+	// Subscribers don't send anything after the handshake, so the only way
+	// to notice they've gone away is a failed read; run that on the side
+	// and select it against the hub feed.
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		var buf [1]byte
+		for {
+			if _, err := conn.Read(buf[:]); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case m := <-ch:
+			if err := protocol.Encode(conn, protocol.MetricsSnapshot(m)); err != nil {
+				logger.Error("subscriber write failed", "err", err)
+				return
+			}
+		case <-disconnected:
+			logger.Debug("subscriber disconnected")
+			return
+		}
 	}
 }
 
 func onReady() {
-	log.Println("systray.OnReady called")
+	logger.Debug("systray.OnReady called")
 	systray.SetTitle("📊")
 	systray.SetTooltip("KawaiiLogger")
 
@@ -105,42 +358,113 @@ func onReady() {
 	mMouseDistance = systray.AddMenuItem("Mouse Travel: 0 in / 0 mi", "Distance moved by mouse")
 	mScrollSteps = systray.AddMenuItem("Scroll Steps: 0", "Number of scroll steps")
 
+	systray.AddSeparator()
+	mToday = systray.AddMenuItem("Today: …", "Totals recorded so far today")
+	mThisWeek = systray.AddMenuItem("This Week: …", "Totals recorded so far this week")
+	mAllTime = systray.AddMenuItem("All Time: …", "Lifetime totals")
+	mExportCSV = systray.AddMenuItem("Export CSV…", "Export every recorded sample as CSV")
+
 	systray.AddSeparator()
 	mQuit := systray.AddMenuItem("Quit", "Quit the application")
 
+	refreshRollups()
+
 	go func() {
-		<-mQuit.ClickedCh
-		log.Println("Quit clicked, cleaning up...")
-		cleanup()
-		systray.Quit()
+		for {
+			select {
+			case <-mToday.ClickedCh:
+				refreshRollups()
+			case <-mThisWeek.ClickedCh:
+				refreshRollups()
+			case <-mAllTime.ClickedCh:
+				refreshRollups()
+			case <-mExportCSV.ClickedCh:
+				exportCSV()
+			case <-mQuit.ClickedCh:
+				logger.Info("quit clicked, cleaning up")
+				cleanup()
+				systray.Quit()
+				return
+			}
+		}
 	}()
 
 	isMenuInitialized = true
-	log.Println("systray.OnReady completed")
+	logger.Debug("systray.OnReady completed")
 }
 
+// refreshRollups recomputes the Today/This Week/All Time menu entries from
+// the metrics store.
+func refreshRollups() {
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	startOfWeek := startOfDay.AddDate(0, 0, -int(now.Weekday()))
+
+	if today, err := metricsStore.TotalsSince(startOfDay); err != nil {
+		logger.Error("failed to compute today's totals", "err", err)
+	} else {
+		mToday.SetTitle(formatTotals("Today", today))
+	}
+
+	if week, err := metricsStore.TotalsSince(startOfWeek); err != nil {
+		logger.Error("failed to compute this week's totals", "err", err)
+	} else {
+		mThisWeek.SetTitle(formatTotals("This Week", week))
+	}
+
+	if allTime, err := metricsStore.AllTimeTotals(); err != nil {
+		logger.Error("failed to compute all-time totals", "err", err)
+	} else {
+		mAllTime.SetTitle(formatTotals("All Time", allTime))
+	}
+}
+
+func formatTotals(label string, t store.Totals) string {
+	return fmt.Sprintf("%s: %d keys, %d clicks, %.1f in, %d scroll", label, t.Keypresses, t.MouseClicks, t.MouseDistanceIn, t.ScrollSteps)
+}
+
+// exportCSV dumps every recorded sample to a timestamped CSV file next to
+// the metrics database.
+func exportCSV() {
+	path := filepath.Join(filepath.Dir(*storeFlag), fmt.Sprintf("kweeb-export-%s.csv", time.Now().Format("20060102-150405")))
+
+	f, err := os.Create(path)
+	if err != nil {
+		logger.Error("failed to create export file", "err", err, "path", path)
+		return
+	}
+	defer f.Close()
+
+	if err := metricsStore.ExportCSV(f); err != nil {
+		logger.Error("failed to export CSV", "err", err)
+		return
+	}
+	logger.Info("exported metrics to CSV", "path", path)
+}
 
 func onExit() {
-	log.Println("systray.OnExit called")
+	logger.Debug("systray.OnExit called")
 	cleanup()
 }
 
 func cleanup() {
-	log.Println("Cleaning up...")
+	logger.Info("cleaning up")
 	if listener != nil {
 		listener.Close()
 	}
-	os.Remove(sockAddr)
+	if metricsStore != nil {
+		metricsStore.Close()
+	}
 }
 
 func updateMenuItems(metrics *Metrics) {
 	if !isMenuInitialized {
-		log.Println("Menu items not initialized, skipping update")
+		logger.Warn("menu items not initialized, skipping update")
 		return
 	}
 
 	if mKeyPresses == nil || mMouseClicks == nil || mMouseDistance == nil || mScrollSteps == nil {
-		log.Println("Menu items are nil, skipping update")
+		logger.Warn("menu items are nil, skipping update")
 		return
 	}
 
@@ -0,0 +1,194 @@
+// Package protocol defines the length-prefixed wire format used between
+// the collector and the menubar process. Every frame is a 4-byte
+// big-endian payload length, a 1-byte message type, a 1-byte schema
+// version, and then a JSON-encoded payload of that length. Reading always
+// goes through io.ReadFull so a message split across multiple reads (or a
+// payload bigger than any fixed buffer) is handled correctly.
+package protocol
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// CurrentVersion is the schema version this build of kweeb-logger writes
+// and the newest version it knows how to read.
+const CurrentVersion byte = 1
+
+const headerSize = 4 + 1 + 1 // length + type + version
+
+// maxPayloadSize bounds the length field so a corrupt or malicious frame
+// can't make Decode allocate up to 4GiB from a single 32-bit length.
+// Metrics payloads are a few dozen bytes; this leaves generous headroom.
+const maxPayloadSize = 4 << 20 // 4 MiB
+
+// Type identifies the kind of payload carried by a frame.
+type Type byte
+
+const (
+	TypeHandshake Type = iota + 1
+	TypeMetricsSnapshot
+	TypeMetricsDelta
+	TypeHeartbeat
+	TypeReset
+)
+
+// Message is implemented by every payload type the protocol can carry.
+type Message interface {
+	Type() Type
+}
+
+// Roles a connection can declare during the handshake. RoleProducer sends
+// metrics frames; RoleSubscriber receives the fan-out instead. A peer that
+// omits Role (e.g. an older client) is treated as a producer for backward
+// compatibility.
+const (
+	RoleProducer   = "producer"
+	RoleSubscriber = "subscriber"
+)
+
+// Handshake is exchanged once right after the transport connects so both
+// sides agree on a schema version, and declare whether this connection
+// will send metrics or receive the fan-out, before any other frame.
+type Handshake struct {
+	Version byte   `json:"version"`
+	Role    string `json:"role,omitempty"`
+}
+
+func (Handshake) Type() Type { return TypeHandshake }
+
+// MetricsSnapshot carries every tracked field, used for the first frame on
+// a connection and periodically thereafter so a late subscriber doesn't
+// have to wait for a delta to learn the current totals.
+type MetricsSnapshot struct {
+	Keypresses      int     `json:"keypresses"`
+	MouseClicks     int     `json:"mouse_clicks"`
+	MouseDistanceIn float64 `json:"mouse_distance_in"`
+	MouseDistanceMi float64 `json:"mouse_distance_mi"`
+	ScrollSteps     int     `json:"scroll_steps"`
+}
+
+func (MetricsSnapshot) Type() Type { return TypeMetricsSnapshot }
+
+// MetricsDelta carries only the fields that changed since the last frame;
+// a nil field means "unchanged". This keeps high-frequency keypress/mouse
+// updates small compared to re-sending every counter each time.
+type MetricsDelta struct {
+	Keypresses      *int     `json:"keypresses,omitempty"`
+	MouseClicks     *int     `json:"mouse_clicks,omitempty"`
+	MouseDistanceIn *float64 `json:"mouse_distance_in,omitempty"`
+	MouseDistanceMi *float64 `json:"mouse_distance_mi,omitempty"`
+	ScrollSteps     *int     `json:"scroll_steps,omitempty"`
+}
+
+func (MetricsDelta) Type() Type { return TypeMetricsDelta }
+
+// Heartbeat carries no data; it lets either side detect a dead peer
+// without waiting on the OS to notice a closed connection.
+type Heartbeat struct{}
+
+func (Heartbeat) Type() Type { return TypeHeartbeat }
+
+// Reset tells the receiver to zero its counters.
+type Reset struct{}
+
+func (Reset) Type() Type { return TypeReset }
+
+// Encode writes msg to w as a single frame.
+func Encode(w io.Writer, msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("protocol: marshal %T: %w", msg, err)
+	}
+
+	header := make([]byte, headerSize)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+	header[4] = byte(msg.Type())
+	header[5] = CurrentVersion
+
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("protocol: write header: %w", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		return fmt.Errorf("protocol: write payload: %w", err)
+	}
+	return nil
+}
+
+// Decode reads and parses a single frame from r, blocking until a full
+// frame has arrived.
+func Decode(r io.Reader) (Message, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	typ := Type(header[4])
+	version := header[5]
+	if version > CurrentVersion {
+		return nil, fmt.Errorf("protocol: frame schema version %d is newer than supported %d", version, CurrentVersion)
+	}
+	if length > maxPayloadSize {
+		return nil, fmt.Errorf("protocol: frame payload of %d bytes exceeds max of %d", length, maxPayloadSize)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("protocol: read payload: %w", err)
+	}
+
+	switch typ {
+	case TypeHandshake:
+		var m Handshake
+		return m, json.Unmarshal(payload, &m)
+	case TypeMetricsSnapshot:
+		var m MetricsSnapshot
+		return m, json.Unmarshal(payload, &m)
+	case TypeMetricsDelta:
+		var m MetricsDelta
+		return m, json.Unmarshal(payload, &m)
+	case TypeHeartbeat:
+		return Heartbeat{}, nil
+	case TypeReset:
+		return Reset{}, nil
+	default:
+		return nil, fmt.Errorf("protocol: unknown message type %d", typ)
+	}
+}
+
+// Negotiate performs the handshake: it sends a Handshake advertising
+// CurrentVersion and localRole, reads the peer's Handshake, and returns the
+// highest schema version both sides understand along with the peer's
+// declared role. Callers should do this once right after the transport
+// connects, before exchanging any other frame.
+func Negotiate(conn io.ReadWriter, localRole string) (version byte, peerRole string, err error) {
+	if err := Encode(conn, Handshake{Version: CurrentVersion, Role: localRole}); err != nil {
+		return 0, "", fmt.Errorf("protocol: send handshake: %w", err)
+	}
+
+	msg, err := Decode(conn)
+	if err != nil {
+		return 0, "", fmt.Errorf("protocol: receive handshake: %w", err)
+	}
+	hs, ok := msg.(Handshake)
+	if !ok {
+		return 0, "", fmt.Errorf("protocol: expected handshake, got %T", msg)
+	}
+
+	agreed := hs.Version
+	if CurrentVersion < agreed {
+		agreed = CurrentVersion
+	}
+	if agreed == 0 {
+		return 0, "", fmt.Errorf("protocol: no common schema version (local=%d, peer=%d)", CurrentVersion, hs.Version)
+	}
+
+	role := hs.Role
+	if role == "" {
+		role = RoleProducer
+	}
+	return agreed, role, nil
+}
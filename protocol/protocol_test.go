@@ -0,0 +1,154 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	cases := []Message{
+		Handshake{Version: CurrentVersion, Role: RoleProducer},
+		MetricsSnapshot{Keypresses: 42, MouseClicks: 7, MouseDistanceIn: 12.5, MouseDistanceMi: 0.0002, ScrollSteps: 3},
+		Heartbeat{},
+		Reset{},
+	}
+
+	for _, msg := range cases {
+		var buf bytes.Buffer
+		if err := Encode(&buf, msg); err != nil {
+			t.Fatalf("Encode(%T) failed: %v", msg, err)
+		}
+
+		got, err := Decode(&buf)
+		if err != nil {
+			t.Fatalf("Decode after Encode(%T) failed: %v", msg, err)
+		}
+		if got != msg {
+			t.Errorf("round trip mismatch: got %#v, want %#v", got, msg)
+		}
+	}
+}
+
+func TestEncodeDecodeMetricsDelta(t *testing.T) {
+	keys := 5
+	delta := MetricsDelta{Keypresses: &keys}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, delta); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	got, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	gotDelta, ok := got.(MetricsDelta)
+	if !ok {
+		t.Fatalf("Decode returned %T, want MetricsDelta", got)
+	}
+	if gotDelta.Keypresses == nil || *gotDelta.Keypresses != keys {
+		t.Errorf("Keypresses = %v, want pointer to %d", gotDelta.Keypresses, keys)
+	}
+	if gotDelta.MouseClicks != nil {
+		t.Errorf("MouseClicks = %v, want nil (unchanged)", gotDelta.MouseClicks)
+	}
+}
+
+func TestDecodeRejectsOversizedLength(t *testing.T) {
+	header := make([]byte, headerSize)
+	binary.BigEndian.PutUint32(header[0:4], maxPayloadSize+1)
+	header[4] = byte(TypeMetricsSnapshot)
+	header[5] = CurrentVersion
+
+	_, err := Decode(bytes.NewReader(header))
+	if err == nil {
+		t.Fatal("Decode did not reject a length above maxPayloadSize")
+	}
+}
+
+func TestDecodeRejectsNewerSchemaVersion(t *testing.T) {
+	header := make([]byte, headerSize)
+	binary.BigEndian.PutUint32(header[0:4], 0)
+	header[4] = byte(TypeHeartbeat)
+	header[5] = CurrentVersion + 1
+
+	_, err := Decode(bytes.NewReader(header))
+	if err == nil {
+		t.Fatal("Decode did not reject a schema version newer than CurrentVersion")
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	// A real loopback connection is used rather than net.Pipe: net.Pipe is
+	// fully synchronous and unbuffered, so two peers that each write their
+	// handshake before reading the other's (exactly what Negotiate does)
+	// deadlock immediately. A TCP socket has the same OS-level buffering
+	// real transports do, so it exercises Negotiate the way production
+	// connections actually behave.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer ln.Close()
+
+	acceptCh := make(chan net.Conn, 1)
+	acceptErrCh := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			acceptErrCh <- err
+			return
+		}
+		acceptCh <- conn
+	}()
+
+	b, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer b.Close()
+
+	var a net.Conn
+	select {
+	case a = <-acceptCh:
+	case err := <-acceptErrCh:
+		t.Fatalf("accept failed: %v", err)
+	}
+	defer a.Close()
+
+	type result struct {
+		version byte
+		role    string
+		err     error
+	}
+	producerResult := make(chan result, 1)
+
+	go func() {
+		version, role, err := Negotiate(a, RoleProducer)
+		producerResult <- result{version, role, err}
+	}()
+
+	version, role, err := Negotiate(b, RoleSubscriber)
+	if err != nil {
+		t.Fatalf("subscriber side Negotiate failed: %v", err)
+	}
+	if version != CurrentVersion {
+		t.Errorf("subscriber side agreed version = %d, want %d", version, CurrentVersion)
+	}
+	if role != RoleProducer {
+		t.Errorf("subscriber side saw peer role %q, want %q", role, RoleProducer)
+	}
+
+	got := <-producerResult
+	if got.err != nil {
+		t.Fatalf("producer side Negotiate failed: %v", got.err)
+	}
+	if got.version != CurrentVersion {
+		t.Errorf("producer side agreed version = %d, want %d", got.version, CurrentVersion)
+	}
+	if got.role != RoleSubscriber {
+		t.Errorf("producer side saw peer role %q, want %q", got.role, RoleSubscriber)
+	}
+}
@@ -0,0 +1,300 @@
+// Package store persists metrics samples to an embedded SQLite database so
+// history survives a collector restart, and exposes the rollup queries the
+// tray menu needs (today, this week, all time, CSV export). It uses
+// modernc.org/sqlite so kweeb-logger keeps building without cgo.
+package store
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	defaultFlushInterval = 5 * time.Second
+	defaultFlushCount    = 50
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS samples (
+	ts INTEGER NOT NULL,
+	keypresses INTEGER NOT NULL,
+	clicks INTEGER NOT NULL,
+	dist_in REAL NOT NULL,
+	scroll INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_samples_ts ON samples(ts);
+`
+
+// Sample is one recorded metrics frame. The counters are the cumulative
+// totals reported by the collector at Timestamp, not deltas.
+type Sample struct {
+	Timestamp       time.Time
+	Keypresses      int
+	MouseClicks     int
+	MouseDistanceIn float64
+	ScrollSteps     int
+}
+
+// Totals is the amount of activity accrued over some window.
+type Totals struct {
+	Keypresses      int
+	MouseClicks     int
+	MouseDistanceIn float64
+	ScrollSteps     int
+}
+
+// Store batches incoming samples in memory and flushes them to SQLite
+// periodically, so high-frequency keypress deltas don't turn into one disk
+// write apiece.
+type Store struct {
+	db *sql.DB
+
+	mu      sync.Mutex
+	pending []Sample
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// Open creates (or reuses) the SQLite database at path, creating parent
+// directories as needed, and starts the background flush loop.
+func Open(path string) (*Store, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("store: creating %s: %w", dir, err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("store: opening %s: %w", path, err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("store: creating schema: %w", err)
+	}
+
+	s := &Store{
+		db:     db,
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+	go s.flushLoop()
+	return s, nil
+}
+
+// Record queues sample for the next flush, flushing immediately once the
+// batch reaches defaultFlushCount.
+func (s *Store) Record(sample Sample) {
+	s.mu.Lock()
+	s.pending = append(s.pending, sample)
+	full := len(s.pending) >= defaultFlushCount
+	s.mu.Unlock()
+
+	if full {
+		s.flush()
+	}
+}
+
+func (s *Store) flushLoop() {
+	defer close(s.doneCh)
+	ticker := time.NewTicker(defaultFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.stopCh:
+			s.flush()
+			return
+		}
+	}
+}
+
+func (s *Store) flush() {
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := s.writeBatch(batch); err != nil {
+		// The write failed for e.g. a transient disk or lock error; put the
+		// batch back ahead of anything recorded since so the next flush
+		// retries it instead of losing it outright.
+		s.mu.Lock()
+		s.pending = append(batch, s.pending...)
+		s.mu.Unlock()
+	}
+}
+
+func (s *Store) writeBatch(batch []Sample) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("store: beginning flush transaction: %w", err)
+	}
+	stmt, err := tx.Prepare(`INSERT INTO samples (ts, keypresses, clicks, dist_in, scroll) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("store: preparing insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, sample := range batch {
+		if _, err := stmt.Exec(sample.Timestamp.Unix(), sample.Keypresses, sample.MouseClicks, sample.MouseDistanceIn, sample.ScrollSteps); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("store: inserting sample: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: committing flush: %w", err)
+	}
+	return nil
+}
+
+// Close flushes any pending samples and closes the database.
+func (s *Store) Close() error {
+	s.stopOnce.Do(func() {
+		close(s.stopCh)
+		<-s.doneCh
+	})
+	return s.db.Close()
+}
+
+// latestTotals returns the most recently recorded cumulative counters, or
+// a zero Totals if nothing has been recorded yet.
+func (s *Store) latestTotals() (Totals, error) {
+	return s.totalsQuery(`SELECT keypresses, clicks, dist_in, scroll FROM samples ORDER BY ts DESC LIMIT 1`)
+}
+
+// baselineBefore returns the cumulative counters as of the last sample
+// strictly before ts, or a zero Totals if there is none.
+func (s *Store) baselineBefore(ts time.Time) (Totals, error) {
+	return s.totalsQuery(`SELECT keypresses, clicks, dist_in, scroll FROM samples WHERE ts < ? ORDER BY ts DESC LIMIT 1`, ts.Unix())
+}
+
+func (s *Store) totalsQuery(query string, args ...any) (Totals, error) {
+	var t Totals
+	err := s.db.QueryRow(query, args...).Scan(&t.Keypresses, &t.MouseClicks, &t.MouseDistanceIn, &t.ScrollSteps)
+	if err == sql.ErrNoRows {
+		return Totals{}, nil
+	}
+	if err != nil {
+		return Totals{}, fmt.Errorf("store: querying totals: %w", err)
+	}
+	return t, nil
+}
+
+// TotalsSince returns how much activity has accrued between since and the
+// most recent sample. The counters are cumulative, but the collector resets
+// them to zero on restart, so a single endpoint subtraction (latest minus
+// baseline) would lose everything recorded before a restart that happens
+// inside the window. Instead this sums the positive increment between each
+// consecutive pair of samples in the window, treating any decrease as a
+// reset: the accrued activity since a reset is whatever the counter reads
+// afterward, not a negative diff.
+func (s *Store) TotalsSince(since time.Time) (Totals, error) {
+	prev, err := s.baselineBefore(since)
+	if err != nil {
+		return Totals{}, err
+	}
+
+	rows, err := s.db.Query(`SELECT keypresses, clicks, dist_in, scroll FROM samples WHERE ts >= ? ORDER BY ts ASC`, since.Unix())
+	if err != nil {
+		return Totals{}, fmt.Errorf("store: querying samples since: %w", err)
+	}
+	defer rows.Close()
+
+	var total Totals
+	for rows.Next() {
+		var cur Totals
+		if err := rows.Scan(&cur.Keypresses, &cur.MouseClicks, &cur.MouseDistanceIn, &cur.ScrollSteps); err != nil {
+			return Totals{}, fmt.Errorf("store: scanning sample: %w", err)
+		}
+
+		total.Keypresses += positiveDeltaInt(cur.Keypresses, prev.Keypresses)
+		total.MouseClicks += positiveDeltaInt(cur.MouseClicks, prev.MouseClicks)
+		total.MouseDistanceIn += positiveDeltaFloat(cur.MouseDistanceIn, prev.MouseDistanceIn)
+		total.ScrollSteps += positiveDeltaInt(cur.ScrollSteps, prev.ScrollSteps)
+		prev = cur
+	}
+	if err := rows.Err(); err != nil {
+		return Totals{}, fmt.Errorf("store: iterating samples: %w", err)
+	}
+	return total, nil
+}
+
+// AllTimeTotals returns the lifetime totals: the activity accrued across
+// every recorded sample, counted the same reset-aware way as TotalsSince.
+func (s *Store) AllTimeTotals() (Totals, error) {
+	return s.TotalsSince(time.Unix(0, 0))
+}
+
+// positiveDeltaInt returns how much an individual counter grew from prev to
+// cur, treating cur < prev as a collector reset rather than a negative diff.
+func positiveDeltaInt(cur, prev int) int {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+func positiveDeltaFloat(cur, prev float64) float64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+// ExportCSV writes every recorded sample, oldest first, to w as CSV with a
+// header row.
+func (s *Store) ExportCSV(w io.Writer) error {
+	rows, err := s.db.Query(`SELECT ts, keypresses, clicks, dist_in, scroll FROM samples ORDER BY ts ASC`)
+	if err != nil {
+		return fmt.Errorf("store: querying samples: %w", err)
+	}
+	defer rows.Close()
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"timestamp", "keypresses", "clicks", "dist_in", "scroll"}); err != nil {
+		return fmt.Errorf("store: writing CSV header: %w", err)
+	}
+
+	for rows.Next() {
+		var ts int64
+		var keypresses, clicks, scroll int
+		var distIn float64
+		if err := rows.Scan(&ts, &keypresses, &clicks, &distIn, &scroll); err != nil {
+			return fmt.Errorf("store: scanning sample: %w", err)
+		}
+		record := []string{
+			time.Unix(ts, 0).Format(time.RFC3339),
+			strconv.Itoa(keypresses),
+			strconv.Itoa(clicks),
+			strconv.FormatFloat(distIn, 'f', 4, 64),
+			strconv.Itoa(scroll),
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("store: writing CSV row: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("store: iterating samples: %w", err)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
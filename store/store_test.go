@@ -0,0 +1,111 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// record writes sample directly and flushes synchronously, bypassing the
+// background flush loop/ticker so tests don't need to sleep.
+func record(t *testing.T, s *Store, sample Sample) {
+	t.Helper()
+	s.Record(sample)
+	s.flush()
+}
+
+func TestTotalsSinceSumsIncrements(t *testing.T) {
+	s := openTestStore(t)
+	base := time.Unix(1700000000, 0)
+
+	record(t, s, Sample{Timestamp: base, Keypresses: 10, MouseClicks: 2, MouseDistanceIn: 1.5, ScrollSteps: 4})
+	record(t, s, Sample{Timestamp: base.Add(time.Hour), Keypresses: 25, MouseClicks: 5, MouseDistanceIn: 3.0, ScrollSteps: 9})
+
+	got, err := s.TotalsSince(base.Add(30 * time.Minute))
+	if err != nil {
+		t.Fatalf("TotalsSince failed: %v", err)
+	}
+	want := Totals{Keypresses: 15, MouseClicks: 3, MouseDistanceIn: 1.5, ScrollSteps: 5}
+	if got != want {
+		t.Errorf("TotalsSince = %+v, want %+v", got, want)
+	}
+}
+
+func TestTotalsSinceCountsActivityAcrossRestart(t *testing.T) {
+	s := openTestStore(t)
+	base := time.Unix(1700000000, 0)
+
+	// A collector restart resets the cumulative counters, so the next
+	// sample can read lower than the baseline before it. The activity
+	// recorded after the restart must still count in full, not get
+	// subtracted away by a single endpoint-to-endpoint diff.
+	record(t, s, Sample{Timestamp: base, Keypresses: 100, MouseClicks: 20, MouseDistanceIn: 9.0, ScrollSteps: 40})
+	record(t, s, Sample{Timestamp: base.Add(time.Hour), Keypresses: 3, MouseClicks: 1, MouseDistanceIn: 0.1, ScrollSteps: 2})
+	record(t, s, Sample{Timestamp: base.Add(2 * time.Hour), Keypresses: 8, MouseClicks: 2, MouseDistanceIn: 0.4, ScrollSteps: 5})
+
+	got, err := s.TotalsSince(base.Add(30 * time.Minute))
+	if err != nil {
+		t.Fatalf("TotalsSince failed: %v", err)
+	}
+	// First post-restart sample (3) counts in full since the counter reset
+	// below the pre-restart baseline (100); the next sample (8) only adds
+	// its increment over 3.
+	want := Totals{Keypresses: 8, MouseClicks: 2, MouseDistanceIn: 0.4, ScrollSteps: 5}
+	if got != want {
+		t.Errorf("TotalsSince across restart = %+v, want %+v", got, want)
+	}
+}
+
+func TestFlushRequeuesBatchOnError(t *testing.T) {
+	s := openTestStore(t)
+	s.Record(Sample{Timestamp: time.Unix(1700000000, 0), Keypresses: 1})
+
+	s.db.Close() // force the next flush to fail
+	s.flush()
+
+	s.mu.Lock()
+	pending := len(s.pending)
+	s.mu.Unlock()
+	if pending != 1 {
+		t.Errorf("pending samples after failed flush = %d, want 1 (batch should be requeued, not dropped)", pending)
+	}
+}
+
+func TestTotalsSinceNoSamples(t *testing.T) {
+	s := openTestStore(t)
+
+	got, err := s.TotalsSince(time.Now())
+	if err != nil {
+		t.Fatalf("TotalsSince failed: %v", err)
+	}
+	if got != (Totals{}) {
+		t.Errorf("TotalsSince on empty store = %+v, want zero value", got)
+	}
+}
+
+func TestAllTimeTotals(t *testing.T) {
+	s := openTestStore(t)
+	base := time.Unix(1700000000, 0)
+
+	record(t, s, Sample{Timestamp: base, Keypresses: 1, MouseClicks: 1, MouseDistanceIn: 0.5, ScrollSteps: 1})
+	record(t, s, Sample{Timestamp: base.Add(time.Minute), Keypresses: 8, MouseClicks: 4, MouseDistanceIn: 2.25, ScrollSteps: 6})
+
+	got, err := s.AllTimeTotals()
+	if err != nil {
+		t.Fatalf("AllTimeTotals failed: %v", err)
+	}
+	want := Totals{Keypresses: 8, MouseClicks: 4, MouseDistanceIn: 2.25, ScrollSteps: 6}
+	if got != want {
+		t.Errorf("AllTimeTotals = %+v, want %+v", got, want)
+	}
+}
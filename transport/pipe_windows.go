@@ -0,0 +1,23 @@
+//go:build windows
+
+package transport
+
+import (
+	"fmt"
+
+	"github.com/Microsoft/go-winio"
+)
+
+const defaultKind = "pipe"
+
+func listenPipe(name string) (Listener, error) {
+	ln, err := winio.ListenPipe(name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("transport: listening on pipe %s: %w", name, err)
+	}
+	return ln, nil
+}
+
+func listenUnix(string) (Listener, error) {
+	return nil, fmt.Errorf("transport: unix domain sockets are not supported on Windows, use --transport=pipe or --transport=tcp")
+}
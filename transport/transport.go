@@ -0,0 +1,64 @@
+// Package transport provides the pluggable IPC listener used by the
+// menubar process to receive metrics from the collector. Concrete
+// transports are selected at runtime via the --transport flag and compiled
+// in per-OS using build tags, so the same call to Listen works whether the
+// collector talks over a Unix domain socket, a Windows named pipe, or a
+// plain TCP loopback connection.
+package transport
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DefaultUnixSocket is the historical socket path kweeb-logger has always
+// used, kept as the default so existing setups keep working unopped.
+const DefaultUnixSocket = "/tmp/kawaiilogger.sock"
+
+// DefaultWindowsPipe is the named pipe used when no --transport flag is
+// given on Windows.
+const DefaultWindowsPipe = `\\.\pipe\kawaiilogger`
+
+// Listener is the cross-platform IPC listener abstraction. Every concrete
+// transport satisfies net.Listener; Listener exists so callers depend on
+// this package's factory instead of reaching for net.Listen directly.
+type Listener interface {
+	net.Listener
+}
+
+// Listen parses spec (the value of the --transport flag) and opens the
+// matching transport. spec is one of:
+//
+//	""                      platform default (unix on Linux/Darwin, named
+//	                        pipe on Windows)
+//	"unix" / "unix:<path>"  Unix domain socket, defaulting to
+//	                        DefaultUnixSocket
+//	"pipe" / "pipe:<name>"  Windows named pipe (Windows only)
+//	"tcp:<host:port>"       TCP loopback fallback, available everywhere
+func Listen(spec string) (Listener, error) {
+	kind, arg, hasArg := strings.Cut(spec, ":")
+	if spec == "" {
+		kind = defaultKind
+	}
+
+	switch kind {
+	case "unix":
+		if !hasArg || arg == "" {
+			arg = DefaultUnixSocket
+		}
+		return listenUnix(arg)
+	case "pipe":
+		if !hasArg || arg == "" {
+			arg = DefaultWindowsPipe
+		}
+		return listenPipe(arg)
+	case "tcp":
+		if !hasArg || arg == "" {
+			return nil, fmt.Errorf("transport: tcp requires a host:port, e.g. --transport=tcp:127.0.0.1:9999")
+		}
+		return net.Listen("tcp", arg)
+	default:
+		return nil, fmt.Errorf("transport: unknown transport %q", kind)
+	}
+}
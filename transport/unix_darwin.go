@@ -0,0 +1,141 @@
+//go:build darwin
+
+package transport
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+const defaultKind = "unix"
+
+// unixListener wraps a net.UnixListener and removes the socket file both on
+// SIGINT/SIGTERM and when the file is deleted out from under it (e.g. by a
+// cleanup script, or Finder), mirroring the kqueue-based socket watcher
+// WireGuard-go uses on Darwin rather than relying solely on signals.
+type unixListener struct {
+	*net.UnixListener
+	path    string
+	sigCh   chan os.Signal
+	closeCh chan struct{}
+	once    sync.Once
+}
+
+func listenUnix(path string) (Listener, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("transport: removing stale socket %s: %w", path, err)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("transport: listening on %s: %w", path, err)
+	}
+
+	ul := &unixListener{
+		UnixListener: ln.(*net.UnixListener),
+		path:         path,
+		sigCh:        make(chan os.Signal, 1),
+		closeCh:      make(chan struct{}),
+	}
+
+	signal.Notify(ul.sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go ul.watchSignals()
+
+	kq, err := watchFileDeleted(path, ul.closeCh)
+	if err == nil {
+		go func() {
+			<-kq
+			// As with watchSignals, just tear down the listener; ending the
+			// process is the caller's decision, not this package's.
+			ul.Close()
+		}()
+	}
+
+	return ul, nil
+}
+
+func (u *unixListener) watchSignals() {
+	select {
+	case <-u.sigCh:
+		// Only unlink the socket file here; it is not this listener's call
+		// to end the process. The caller's Accept loop will unblock with an
+		// error once Close runs, and the caller decides how to shut down.
+		u.Close()
+	case <-u.closeCh:
+	}
+}
+
+func (u *unixListener) Close() error {
+	var err error
+	u.once.Do(func() {
+		signal.Stop(u.sigCh)
+		close(u.closeCh)
+		err = u.UnixListener.Close()
+		os.Remove(u.path)
+	})
+	return err
+}
+
+// watchFileDeleted uses a kqueue EVFILT_VNODE watch to detect the socket
+// file being unlinked externally (rather than through Close), returning a
+// channel that fires once when that happens. It is best-effort: if the
+// kqueue can't be set up, callers fall back to signal-only cleanup.
+func watchFileDeleted(path string, stop <-chan struct{}) (<-chan struct{}, error) {
+	fd, err := syscall.Open(path, syscall.O_EVTONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	kq, err := syscall.Kqueue()
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	ev := syscall.Kevent_t{
+		Ident:  uint64(fd),
+		Filter: syscall.EVFILT_VNODE,
+		Flags:  syscall.EV_ADD | syscall.EV_CLEAR,
+		Fflags: syscall.NOTE_DELETE,
+	}
+
+	if _, err := syscall.Kevent(kq, []syscall.Kevent_t{ev}, nil, nil); err != nil {
+		syscall.Close(fd)
+		syscall.Close(kq)
+		return nil, err
+	}
+
+	deleted := make(chan struct{})
+	go func() {
+		defer syscall.Close(fd)
+		defer syscall.Close(kq)
+		events := make([]syscall.Kevent_t, 1)
+		for {
+			n, err := syscall.Kevent(kq, nil, events, nil)
+			if err != nil {
+				return
+			}
+			for i := 0; i < n; i++ {
+				if events[i].Fflags&syscall.NOTE_DELETE != 0 {
+					close(deleted)
+					return
+				}
+			}
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+	}()
+
+	return deleted, nil
+}
+
+func listenPipe(string) (Listener, error) {
+	return nil, fmt.Errorf("transport: named pipes are only supported on Windows")
+}
@@ -0,0 +1,74 @@
+//go:build linux
+
+package transport
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+const defaultKind = "unix"
+
+// unixListener wraps a net.UnixListener and guarantees the socket file is
+// removed if the process receives SIGINT/SIGTERM, in addition to the
+// removal net.UnixListener already does on a clean Close.
+type unixListener struct {
+	*net.UnixListener
+	path    string
+	sigCh   chan os.Signal
+	closeCh chan struct{}
+	once    sync.Once
+}
+
+func listenUnix(path string) (Listener, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("transport: removing stale socket %s: %w", path, err)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("transport: listening on %s: %w", path, err)
+	}
+
+	ul := &unixListener{
+		UnixListener: ln.(*net.UnixListener),
+		path:         path,
+		sigCh:        make(chan os.Signal, 1),
+		closeCh:      make(chan struct{}),
+	}
+
+	signal.Notify(ul.sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go ul.watchSignals()
+
+	return ul, nil
+}
+
+func (u *unixListener) watchSignals() {
+	select {
+	case <-u.sigCh:
+		// Only unlink the socket file here; it is not this listener's call
+		// to end the process. The caller's Accept loop will unblock with an
+		// error once Close runs, and the caller decides how to shut down.
+		u.Close()
+	case <-u.closeCh:
+	}
+}
+
+func (u *unixListener) Close() error {
+	var err error
+	u.once.Do(func() {
+		signal.Stop(u.sigCh)
+		close(u.closeCh)
+		err = u.UnixListener.Close()
+		os.Remove(u.path)
+	})
+	return err
+}
+
+func listenPipe(string) (Listener, error) {
+	return nil, fmt.Errorf("transport: named pipes are only supported on Windows")
+}